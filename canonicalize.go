@@ -0,0 +1,117 @@
+package mimetype
+
+import (
+	"log"
+	"strings"
+)
+
+// Logger receives the warnings Canonicalize emits. It is satisfied by
+// *log.Logger, so most callers can pass their existing logger unchanged.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+var canonicalizeLogger Logger = log.Default()
+
+// SetLogger replaces the logger Canonicalize warns through. Passing nil
+// restores the default, which logs via the standard library's log package.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = log.Default()
+	}
+	canonicalizeLogger = l
+}
+
+// Canonicalize rewrites m's canonical MIME string to newType, demoting its
+// previous string into its alias set. It exists for callers who disagree
+// with this module's canonical choice between two equally valid strings
+// (audio/wav vs audio/x-wav, application/x-rar-compressed vs
+// application/vnd.rar, image/heic-sequence vs image/heic, ...) and today
+// have to either monkey-patch newMIME or post-process every Detect result.
+func (m *MIME) Canonicalize(newType string) {
+	// Must happen before mu.Lock below: buildExtIndex takes mu.RLock
+	// itself, and Go's RWMutex is not reentrant.
+	buildExtIndex()
+	mu.Lock()
+	defer mu.Unlock()
+	m.canonicalizeLocked(newType, nil)
+}
+
+// Canonicalize looks up oldType in the tree and renames it to newType, as
+// (*MIME).Canonicalize does, additionally migrating extensions into the
+// reverse lookup index used by LookupByExtension and ExtensionsFor. It
+// returns the renamed node, or nil if oldType is not known to the tree.
+func Canonicalize(oldType, newType string, extensions ...string) *MIME {
+	// Must happen before mu.Lock below: buildExtIndex takes mu.RLock
+	// itself, and Go's RWMutex is not reentrant. Doing this unconditionally
+	// (rather than only inside canonicalizeLocked, past the point where a
+	// nil extToMIME used to silently drop the extensions vararg) ensures
+	// the index always exists by the time extensions are recorded into it.
+	buildExtIndex()
+	mu.Lock()
+	defer mu.Unlock()
+	m := findMIME(oldType)
+	if m == nil {
+		return nil
+	}
+	m.canonicalizeLocked(newType, extensions)
+	return m
+}
+
+// canonicalizeLocked does the work behind both Canonicalize entry points;
+// callers must hold mu for writing. Overriding a type that already has
+// children or aliases is easy to get wrong silently (existing Detect
+// callers keyed off the old string stop matching), so it is worth a warning
+// through the configured Logger.
+func (m *MIME) canonicalizeLocked(newType string, extensions []string) {
+	if newType == m.mime {
+		return
+	}
+	if len(m.children) > 0 || len(m.aliases) > 0 {
+		canonicalizeLogger.Printf(
+			"mimetype: Canonicalize(%q, %q): overriding a type with %d children and %d aliases",
+			m.mime, newType, len(m.children), len(m.aliases))
+	}
+
+	old := m.mime
+	m.mime = newType
+
+	// newType may already be one of m's aliases (Canonicalize("audio/wav",
+	// "audio/x-wav") is exactly this: wav already aliases "audio/x-wav").
+	// Drop it from the alias set before adding old, or old ends up listed
+	// as both the canonical string and, redundantly, its own alias.
+	for i, a := range m.aliases {
+		if a == newType {
+			m.aliases = append(m.aliases[:i], m.aliases[i+1:]...)
+			break
+		}
+	}
+	if !containsString(m.aliases, old) {
+		m.aliases = append(m.aliases, old)
+	}
+
+	if extToMIME == nil {
+		// Both Canonicalize entry points call buildExtIndex before taking
+		// mu, so this only guards direct, non-exported misuse.
+		return
+	}
+	mimeToExts[newType] = append(mimeToExts[newType], mimeToExts[old]...)
+	delete(mimeToExts, old)
+	for _, ext := range extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extToMIME[ext] = m
+		mimeToExts[newType] = append(mimeToExts[newType], ext)
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
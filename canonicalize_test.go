@@ -0,0 +1,56 @@
+package mimetype
+
+import "testing"
+
+// TestCanonicalizeExistingAlias exercises the request's own worked example:
+// audio/wav already aliases audio/x-wav, so renaming to that alias must not
+// leave audio/x-wav listed both as the canonical string and, redundantly,
+// as its own alias.
+func TestCanonicalizeExistingAlias(t *testing.T) {
+	m := Canonicalize("audio/wav", "audio/x-wav")
+	if m == nil {
+		t.Fatal("Canonicalize(\"audio/wav\", \"audio/x-wav\") = nil, want the wav node")
+	}
+	if m.mime != "audio/x-wav" {
+		t.Errorf("m.mime = %q, want %q", m.mime, "audio/x-wav")
+	}
+
+	seen := map[string]int{}
+	for _, a := range m.aliases {
+		seen[a]++
+	}
+	if n := seen["audio/x-wav"]; n != 0 {
+		t.Errorf("aliases contain %q %d time(s), want 0 (it is now the canonical string)", "audio/x-wav", n)
+	}
+	if n := seen["audio/wav"]; n != 1 {
+		t.Errorf("aliases contain %q %d time(s), want exactly 1", "audio/wav", n)
+	}
+}
+
+// TestCanonicalizeExtensionsVararg guards against a regression where, if
+// the extension index hadn't been built yet, canonicalizeLocked returned
+// before recording the extensions vararg at all, silently losing it: since
+// buildExtIndex only ever reconstructs the index from each node's own
+// m.extension field, an extension passed here had no other way back in.
+func TestCanonicalizeExtensionsVararg(t *testing.T) {
+	m := Canonicalize("audio/aiff", "audio/x-mimetype-canon-test", ".mtcanontest")
+	if m == nil {
+		t.Fatal("Canonicalize(\"audio/aiff\", ...) = nil, want the aiff node")
+	}
+
+	exts := ExtensionsFor("audio/x-mimetype-canon-test")
+	if !containsString(exts, ".mtcanontest") {
+		t.Errorf("ExtensionsFor(%q) = %v, want it to include %q", "audio/x-mimetype-canon-test", exts, ".mtcanontest")
+	}
+	if got := LookupByExtension(".mtcanontest"); got != m {
+		t.Errorf("LookupByExtension(%q) = %v, want the renamed node", ".mtcanontest", got)
+	}
+}
+
+func TestMIMECanonicalizeNoop(t *testing.T) {
+	before := len(png.aliases)
+	png.Canonicalize("image/png")
+	if len(png.aliases) != before {
+		t.Errorf("Canonicalize to the same type changed aliases: got %v", png.aliases)
+	}
+}
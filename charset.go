@@ -0,0 +1,244 @@
+package mimetype
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Result is the outcome of DetectFull: the detected MIME and its canonical
+// extension, plus, for textual types, a best-effort character encoding with
+// a confidence score in [0, 1]. Charset is left empty for binary types,
+// where the concept does not apply.
+type Result struct {
+	MIME       *MIME
+	Extension  string
+	Charset    string
+	Confidence float64
+}
+
+// textualRoots lists the tree nodes whose subtree DetectFull runs charset
+// sniffing against. Detecting an encoding for, say, image/png would be
+// meaningless, so anything not descended from one of these gets no Charset.
+var textualRoots = []*MIME{text, xml, json, html, csv, svg}
+
+func isTextual(m *MIME) bool {
+	for cur := m; cur != nil; cur = cur.parent {
+		for _, r := range textualRoots {
+			if cur == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DetectFull behaves like Detect, additionally reporting the extension and,
+// for textual types, the character encoding of in.
+func DetectFull(in []byte) Result {
+	m := Detect(in)
+	r := Result{MIME: m, Extension: m.Extension()}
+	if isTextual(m) {
+		r.Charset, r.Confidence = detectCharset(in)
+	}
+	return r
+}
+
+// charsetSniffLimit bounds how much of a reader or file DetectReaderFull and
+// DetectFileFull buffer before running detection. Charset sniffing benefits
+// from more context than the header alone, so this is wider than a typical
+// magic-sniffing window, without reading arbitrarily large inputs in full.
+const charsetSniffLimit = 8192
+
+// DetectReaderFull behaves like DetectFull but reads its input from r.
+func DetectReaderFull(r io.Reader) (Result, error) {
+	in, err := io.ReadAll(io.LimitReader(r, charsetSniffLimit))
+	if err != nil {
+		return Result{}, err
+	}
+	return DetectFull(in), nil
+}
+
+// DetectFileFull behaves like DetectFull but reads its input from the file
+// at path.
+func DetectFileFull(path string) (Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{}, err
+	}
+	defer f.Close()
+	return DetectReaderFull(f)
+}
+
+// bomTable lists recognized byte-order marks, longest first so a 4-byte
+// UTF-32LE BOM is not mistaken for the UTF-16LE BOM it starts with.
+var bomTable = []struct {
+	bom     []byte
+	charset string
+}{
+	{[]byte{0xFF, 0xFE, 0x00, 0x00}, "UTF-32LE"},
+	{[]byte{0x00, 0x00, 0xFE, 0xFF}, "UTF-32BE"},
+	{[]byte{0xEF, 0xBB, 0xBF}, "UTF-8"},
+	{[]byte{0x2B, 0x2F, 0x76}, "UTF-7"},
+	{[]byte{0xFF, 0xFE}, "UTF-16LE"},
+	{[]byte{0xFE, 0xFF}, "UTF-16BE"},
+}
+
+var (
+	metaCharsetRe = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?([a-zA-Z0-9_-]+)`)
+	xmlDeclRe     = regexp.MustCompile(`(?i)<\?xml[^>]+encoding\s*=\s*["']([a-zA-Z0-9_-]+)["']`)
+)
+
+// detectCharset runs, in order of decreasing certainty: a byte-order-mark
+// check, an HTML <meta charset> / XML declaration sniff, then a statistical
+// detector, returning the first one that yields an answer.
+func detectCharset(in []byte) (string, float64) {
+	if cs, ok := bomCharset(in); ok {
+		return cs, 1
+	}
+	if cs, ok := declaredCharset(in); ok {
+		return cs, 0.9
+	}
+	return statisticalCharset(in)
+}
+
+func bomCharset(in []byte) (string, bool) {
+	for _, b := range bomTable {
+		if bytes.HasPrefix(in, b.bom) {
+			return b.charset, true
+		}
+	}
+	return "", false
+}
+
+// declaredCharset looks for an explicit encoding declaration in the first
+// KiB of in, the way browsers and XML parsers do before falling back to
+// sniffing.
+func declaredCharset(in []byte) (string, bool) {
+	head := in
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if m := metaCharsetRe.FindSubmatch(head); m != nil {
+		return strings.ToUpper(string(m[1])), true
+	}
+	if m := xmlDeclRe.FindSubmatch(head); m != nil {
+		return strings.ToUpper(string(m[1])), true
+	}
+	return "", false
+}
+
+// statisticalCharset tries candidate encodings against in, in this order: a
+// NUL-byte parity check for UTF-16, a continuation-byte validity ratio for
+// UTF-8, and an ASCII/high-bit split for the single-byte encodings. It
+// returns the first candidate whose check passes, with that check's score as
+// the confidence; the scores of different candidates are not compared.
+func statisticalCharset(in []byte) (string, float64) {
+	if len(in) == 0 {
+		return "UTF-8", 1
+	}
+	if cs, confidence, ok := scoreUTF16(in); ok {
+		return cs, confidence
+	}
+	if valid, ratio := scoreUTF8(in); valid {
+		return "UTF-8", ratio
+	}
+	return scoreSingleByte(in)
+}
+
+// scoreUTF16 reports the likely UTF-16 variant of in by comparing how many
+// NUL bytes fall on even versus odd offsets: text in a wide encoding pads
+// every ASCII character with a NUL on the opposite parity from where the
+// character byte itself sits.
+func scoreUTF16(in []byte) (string, float64, bool) {
+	var evenNul, oddNul int
+	for i, b := range in {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenNul++
+		} else {
+			oddNul++
+		}
+	}
+	total := evenNul + oddNul
+	if total == 0 || float64(total)/float64(len(in)) < 0.2 {
+		return "", 0, false
+	}
+	if evenNul > oddNul*3 {
+		return "UTF-16BE", float64(evenNul) / float64(total), true
+	}
+	if oddNul > evenNul*3 {
+		return "UTF-16LE", float64(oddNul) / float64(total), true
+	}
+	return "", 0, false
+}
+
+// scoreUTF8 walks in as UTF-8, validating each multi-byte sequence's
+// continuation bytes, and reports whether the ratio of valid multi-byte
+// sequences to all multi-byte sequences is high enough to call it UTF-8.
+func scoreUTF8(in []byte) (bool, float64) {
+	valid, total := 0, 0
+	for i := 0; i < len(in); {
+		b := in[i]
+		switch {
+		case b < 0x80:
+			i++
+		case b&0xE0 == 0xC0:
+			total++
+			if i+1 < len(in) && in[i+1]&0xC0 == 0x80 {
+				valid++
+			}
+			i += 2
+		case b&0xF0 == 0xE0:
+			total++
+			if i+2 < len(in) && in[i+1]&0xC0 == 0x80 && in[i+2]&0xC0 == 0x80 {
+				valid++
+			}
+			i += 3
+		case b&0xF8 == 0xF0:
+			total++
+			if i+3 < len(in) && in[i+1]&0xC0 == 0x80 && in[i+2]&0xC0 == 0x80 && in[i+3]&0xC0 == 0x80 {
+				valid++
+			}
+			i += 4
+		default:
+			total++
+			i++
+		}
+	}
+	if total == 0 {
+		return true, 1
+	}
+	ratio := float64(valid) / float64(total)
+	return ratio > 0.9, ratio
+}
+
+// scoreSingleByte falls back to a single-byte encoding when in is neither
+// UTF-16 nor valid UTF-8: pure ASCII is reported as such, bytes in the
+// 0x80-0x9F range that ISO-8859-1 leaves undefined but windows-1252 assigns
+// to printable characters point at windows-1252, and anything else is
+// assumed to be ISO-8859-1, the most common ISO-8859 variant in the wild.
+func scoreSingleByte(in []byte) (string, float64) {
+	var ascii, highBit, cp1252Only int
+	for _, b := range in {
+		if b < 0x80 {
+			ascii++
+			continue
+		}
+		highBit++
+		if b >= 0x80 && b <= 0x9F {
+			cp1252Only++
+		}
+	}
+	if highBit == 0 {
+		return "US-ASCII", 1
+	}
+	if cp1252Only > 0 {
+		return "windows-1252", float64(cp1252Only) / float64(highBit)
+	}
+	return "ISO-8859-1", float64(ascii) / float64(len(in))
+}
@@ -0,0 +1,79 @@
+package mimetype
+
+import "testing"
+
+func TestBomCharset(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"utf-8", []byte{0xEF, 0xBB, 0xBF, 'a'}, "UTF-8"},
+		{"utf-16le", []byte{0xFF, 0xFE, 'a', 0}, "UTF-16LE"},
+		{"utf-16be", []byte{0xFE, 0xFF, 0, 'a'}, "UTF-16BE"},
+		{"utf-32le", []byte{0xFF, 0xFE, 0, 0, 'a', 0, 0, 0}, "UTF-32LE"},
+		{"utf-32be", []byte{0, 0, 0xFE, 0xFF, 0, 0, 0, 'a'}, "UTF-32BE"},
+	}
+	for _, tt := range tests {
+		got, ok := bomCharset(tt.in)
+		if !ok || got != tt.want {
+			t.Errorf("%s: bomCharset() = (%q, %v), want (%q, true)", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestScoreUTF16(t *testing.T) {
+	// "abcde" as UTF-16LE: low byte, 0x00, low byte, 0x00, ...
+	le := []byte{'a', 0, 'b', 0, 'c', 0, 'd', 0, 'e', 0}
+	cs, _, ok := scoreUTF16(le)
+	if !ok || cs != "UTF-16LE" {
+		t.Errorf("scoreUTF16(LE) = (%q, %v), want UTF-16LE", cs, ok)
+	}
+
+	be := []byte{0, 'a', 0, 'b', 0, 'c', 0, 'd', 0, 'e'}
+	cs, _, ok = scoreUTF16(be)
+	if !ok || cs != "UTF-16BE" {
+		t.Errorf("scoreUTF16(BE) = (%q, %v), want UTF-16BE", cs, ok)
+	}
+
+	if _, _, ok := scoreUTF16([]byte("plain ascii text with no nulls")); ok {
+		t.Error("scoreUTF16(ascii) = ok, want not ok")
+	}
+}
+
+func TestScoreUTF8(t *testing.T) {
+	valid, ratio := scoreUTF8([]byte("héllo wörld"))
+	if !valid || ratio < 0.9 {
+		t.Errorf("scoreUTF8(valid utf-8) = (%v, %v), want (true, >=0.9)", valid, ratio)
+	}
+
+	// 0xE9 alone (Latin-1 'é') is not a valid UTF-8 lead byte in context.
+	invalid, _ := scoreUTF8([]byte{'h', 0xE9, 'l', 'l', 'o'})
+	if invalid {
+		t.Error("scoreUTF8(latin-1 bytes) = true, want false")
+	}
+}
+
+func TestDeclaredCharset(t *testing.T) {
+	html := []byte(`<html><head><meta charset="ISO-8859-1"></head></html>`)
+	if got, ok := declaredCharset(html); !ok || got != "ISO-8859-1" {
+		t.Errorf("declaredCharset(meta) = (%q, %v), want (%q, true)", got, ok, "ISO-8859-1")
+	}
+
+	xmlDoc := []byte(`<?xml version="1.0" encoding="Windows-1252"?><root/>`)
+	if got, ok := declaredCharset(xmlDoc); !ok || got != "WINDOWS-1252" {
+		t.Errorf("declaredCharset(xml decl) = (%q, %v), want (%q, true)", got, ok, "WINDOWS-1252")
+	}
+
+	if _, ok := declaredCharset([]byte("no declaration here")); ok {
+		t.Error("declaredCharset(plain text) = ok, want not ok")
+	}
+}
+
+func TestDetectFullBinaryHasNoCharset(t *testing.T) {
+	pngBytes := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+	r := DetectFull(pngBytes)
+	if r.Charset != "" {
+		t.Errorf("DetectFull(png).Charset = %q, want empty for a binary type", r.Charset)
+	}
+}
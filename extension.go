@@ -0,0 +1,198 @@
+package mimetype
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// extraExtensions holds extension -> canonical MIME associations that are
+// absent from magic detection: no node in the tree registers them, and
+// nothing about their bytes alone identifies them. LookupByExtension falls
+// back to this table when the tree itself has no node for ext.
+//
+// The macro-enabled and template OOXML extensions this table used to list
+// (.docm, .xlsm, .dotx, ...) moved out once they got real magic detectors
+// and tree nodes of their own; keeping them here too would have left two
+// hand-maintained sources of truth for the same extension.
+var extraExtensions = map[string]string{
+	".css": "text/css",
+}
+
+var (
+	extIndexOnce sync.Once
+	extToMIME    map[string]*MIME
+	mimeToExts   map[string][]string
+)
+
+// buildExtIndex walks the tree once, recording each node's extension keyed
+// by its lowercased form, plus the reverse mapping from MIME string to every
+// extension resolving to it. extraExtensions fills in the gaps left by
+// nodes newMIME never registered an extension for. The result is cached for
+// the lifetime of the process; Canonicalize is responsible for keeping it in
+// sync when a type is renamed after the index has been built.
+func buildExtIndex() {
+	extIndexOnce.Do(func() {
+		mu.RLock()
+		defer mu.RUnlock()
+		extToMIME = make(map[string]*MIME)
+		mimeToExts = make(map[string][]string)
+
+		var walk func(m *MIME)
+		walk = func(m *MIME) {
+			if m.extension != "" {
+				ext := strings.ToLower(m.extension)
+				if _, ok := extToMIME[ext]; !ok {
+					extToMIME[ext] = m
+					mimeToExts[m.mime] = append(mimeToExts[m.mime], ext)
+				}
+			}
+			for _, c := range m.children {
+				walk(c)
+			}
+		}
+		walk(root)
+
+		for ext, mime := range extraExtensions {
+			ext = strings.ToLower(ext)
+			if _, ok := extToMIME[ext]; ok {
+				continue
+			}
+			extToMIME[ext] = newMIME(mime, ext, func([]byte, uint32) bool { return false })
+			mimeToExts[mime] = append(mimeToExts[mime], ext)
+		}
+	})
+}
+
+// LookupByExtension returns the MIME registered for ext, which may be given
+// with or without its leading dot (".png" and "png" are equivalent), or nil
+// when neither the tree nor the extra table linked in extraExtensions know
+// about it. Unlike Detect, LookupByExtension needs no file content, so it
+// can resolve a type purely from a filename.
+func LookupByExtension(ext string) *MIME {
+	if ext == "" {
+		return nil
+	}
+	buildExtIndex()
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	ext = strings.ToLower(ext)
+
+	// buildExtIndex only guarantees extToMIME exists; Canonicalize and
+	// RegisterFromMagic/LoadFromXML can still mutate it under mu.Lock at
+	// any later time, so reading it here needs the matching read lock.
+	mu.RLock()
+	defer mu.RUnlock()
+	return extToMIME[ext]
+}
+
+// ExtensionsFor returns every extension known to resolve to mime, the
+// canonical string of a MIME type such as "image/png". It returns nil when
+// mime is not present anywhere in the tree or the extra table.
+func ExtensionsFor(mime string) []string {
+	buildExtIndex()
+	mu.RLock()
+	defer mu.RUnlock()
+	return mimeToExts[mime]
+}
+
+// DetectOption configures the extension-fallback behaviour of
+// DetectWithOptions and DetectReaderWithOptions.
+type DetectOption func(*detectOptions)
+
+type detectOptions struct {
+	fallbackExt string
+}
+
+// WithExtensionFallback makes the detection call consult
+// LookupByExtension(ext) whenever the magic-based result is the generic
+// application/octet-stream, the result byte sniffing yields when no detector
+// in the tree recognizes the content.
+func WithExtensionFallback(ext string) DetectOption {
+	return func(o *detectOptions) { o.fallbackExt = ext }
+}
+
+func withFallback(m *MIME, opts []DetectOption) *MIME {
+	if m == nil || m.mime != "application/octet-stream" || len(opts) == 0 {
+		return m
+	}
+	o := &detectOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.fallbackExt == "" {
+		return m
+	}
+	if byExt := LookupByExtension(o.fallbackExt); byExt != nil {
+		return byExt
+	}
+	return m
+}
+
+// DetectWithOptions behaves like Detect but additionally accepts
+// DetectOptions such as WithExtensionFallback.
+func DetectWithOptions(in []byte, opts ...DetectOption) *MIME {
+	return withFallback(Detect(in), opts)
+}
+
+// DetectReaderWithOptions behaves like DetectReader but additionally accepts
+// DetectOptions such as WithExtensionFallback.
+func DetectReaderWithOptions(r io.Reader, opts ...DetectOption) (*MIME, error) {
+	m, err := DetectReader(r)
+	if err != nil {
+		return m, err
+	}
+	return withFallback(m, opts), nil
+}
+
+// FileDetection is the result of DetectFile: the magic-based and
+// extension-based guesses for a file, fused into a single best answer, with
+// Mismatch flagging when the two disagree.
+type FileDetection struct {
+	// MIME is the best guess: ByMagic, unless magic sniffing could only
+	// narrow the file down to application/octet-stream and ByExt found
+	// something more specific.
+	MIME *MIME
+	// ByMagic is the result of sniffing the file's content.
+	ByMagic *MIME
+	// ByExt is the result of looking up the file's extension, or nil if
+	// the extension is unknown.
+	ByExt *MIME
+	// Mismatch is true when ByExt is known and disagrees with ByMagic.
+	// Content is the stronger signal, so callers porting scripts from
+	// `file -bi` that trusted extensions should treat this as a warning,
+	// not necessarily as ByExt being wrong.
+	Mismatch bool
+}
+
+// DetectFile reads path and fuses a magic-based detection with an
+// extension-based lookup, reporting when the two disagree. This is the
+// common ask from users porting from `file -bi`, which only inspects
+// content. The extension-based result replaces the magic-based one only
+// when the content resolves to nothing more specific than
+// application/octet-stream.
+func DetectFile(path string) (FileDetection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileDetection{}, err
+	}
+	defer f.Close()
+
+	byMagic, err := DetectReader(f)
+	if err != nil {
+		return FileDetection{}, err
+	}
+	byExt := LookupByExtension(filepath.Ext(path))
+
+	fd := FileDetection{MIME: byMagic, ByMagic: byMagic, ByExt: byExt}
+	if byExt != nil {
+		fd.Mismatch = byExt.mime != byMagic.mime
+		if byMagic.mime == "application/octet-stream" {
+			fd.MIME = byExt
+		}
+	}
+	return fd, nil
+}
@@ -0,0 +1,59 @@
+package mimetype
+
+import "testing"
+
+func TestLookupByExtension(t *testing.T) {
+	tests := []struct {
+		ext  string
+		want string
+	}{
+		{".png", "image/png"},
+		{"png", "image/png"},
+		{".PNG", "image/png"},
+		{".css", "text/css"},
+		{".does-not-exist", ""},
+	}
+	for _, tt := range tests {
+		got := LookupByExtension(tt.ext)
+		if tt.want == "" {
+			if got != nil {
+				t.Errorf("LookupByExtension(%q) = %q, want nil", tt.ext, got.String())
+			}
+			continue
+		}
+		if got == nil || got.String() != tt.want {
+			t.Errorf("LookupByExtension(%q) = %v, want %q", tt.ext, got, tt.want)
+		}
+	}
+}
+
+func TestExtensionsFor(t *testing.T) {
+	got := ExtensionsFor("image/png")
+	if len(got) != 1 || got[0] != ".png" {
+		t.Errorf("ExtensionsFor(%q) = %v, want [%q]", "image/png", got, ".png")
+	}
+	if got := ExtensionsFor("does/not-exist"); got != nil {
+		t.Errorf("ExtensionsFor(unknown) = %v, want nil", got)
+	}
+}
+
+// TestLookupByExtensionAfterRegisterFromMagic guards against a regression
+// where RegisterFromMagic consumed extIndexOnce with a throwaway closure,
+// permanently preventing the real index from ever being built: any lookup,
+// for any extension, registered before or after, would then silently
+// return nil for the rest of the process.
+func TestLookupByExtensionAfterRegisterFromMagic(t *testing.T) {
+	specs := []MagicSpec{{Offset: 0, Type: "string", Value: []byte("XTEST")}}
+	if _, err := RegisterFromMagic("application/x-mimetype-test", ".xtest", nil, specs); err != nil {
+		t.Fatalf("RegisterFromMagic: %v", err)
+	}
+
+	if got := LookupByExtension(".xtest"); got == nil || got.String() != "application/x-mimetype-test" {
+		t.Errorf("LookupByExtension(%q) = %v, want %q", ".xtest", got, "application/x-mimetype-test")
+	}
+	// A pre-existing extension must still resolve; a nil-forever index
+	// would fail this too.
+	if got := LookupByExtension(".png"); got == nil || got.String() != "image/png" {
+		t.Errorf("LookupByExtension(%q) = %v, want %q", ".png", got, "image/png")
+	}
+}
@@ -0,0 +1,205 @@
+package magic
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+)
+
+// localFileHeaderSig is the 4-byte signature that opens every ZIP local
+// file header. Unlike the central directory, which sits at the end of the
+// archive, local file headers are interleaved with entry data starting at
+// offset 0, so they are the only ZIP records a bounded sniffing prefix is
+// guaranteed to contain.
+const localFileHeaderSig = 0x04034b50
+
+// zipLocalFile is one entry as read directly from a local file header.
+type zipLocalFile struct {
+	name      string
+	method    uint16
+	data      []byte // as much of the entry's compressed bytes as fit in raw
+	truncated bool   // true when raw ran out before compSize bytes were read
+}
+
+// walkZipLocalFiles forward-scans raw for consecutive local file headers,
+// stopping at the first record that isn't one (typically the start of the
+// central directory) or when a header claims more data than raw has left.
+// It never needs the end-of-central-directory record, which is what makes
+// it usable on a truncated sniffing prefix, unlike archive/zip.Reader.
+func walkZipLocalFiles(raw []byte) []zipLocalFile {
+	var files []zipLocalFile
+	for off := 0; off+30 <= len(raw); {
+		if binary.LittleEndian.Uint32(raw[off:off+4]) != localFileHeaderSig {
+			break
+		}
+		method := binary.LittleEndian.Uint16(raw[off+8 : off+10])
+		compSize := binary.LittleEndian.Uint32(raw[off+18 : off+22])
+		nameLen := int(binary.LittleEndian.Uint16(raw[off+26 : off+28]))
+		extraLen := int(binary.LittleEndian.Uint16(raw[off+28 : off+30]))
+
+		nameStart := off + 30
+		nameEnd := nameStart + nameLen
+		if nameEnd > len(raw) {
+			break
+		}
+		dataStart := nameEnd + extraLen
+		if dataStart > len(raw) {
+			break
+		}
+		dataEnd := dataStart + int(compSize)
+		truncated := dataEnd > len(raw)
+		if truncated {
+			dataEnd = len(raw)
+		}
+
+		files = append(files, zipLocalFile{
+			name:      string(raw[nameStart:nameEnd]),
+			method:    method,
+			data:      raw[dataStart:dataEnd],
+			truncated: truncated,
+		})
+		if truncated {
+			// The next header, if any, is past the end of raw; stop
+			// rather than mis-parse whatever bytes happen to follow.
+			break
+		}
+		off = dataEnd
+	}
+	return files
+}
+
+// inflate decompresses a raw DEFLATE stream (ZIP compression method 8).
+func inflate(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// ooxmlHasVBA reports whether raw, a prefix of an OOXML ZIP, lists a VBA
+// project under the given app directory (e.g. "word/", "xl/", "ppt/"). The
+// part's name alone, visible in its local file header, is enough; its
+// content never needs to be read. This only confirms that the named app's
+// package contains macros, not which of that app's several macro-enabled
+// subtypes (document vs template, say) it is; the content-type marker is
+// what disambiguates those.
+func ooxmlHasVBA(raw []byte, appDir string) bool {
+	want := appDir + "vbaProject.bin"
+	for _, f := range walkZipLocalFiles(raw) {
+		if f.name == want {
+			return true
+		}
+	}
+	return false
+}
+
+// ooxmlContentType reports whether raw, a prefix of an OOXML ZIP, declares
+// a part in [Content_Types].xml whose Override ContentType contains marker.
+// This is how OOXML documents advertise their exact flavour (a
+// macro-enabled workbook vs a plain one, say) even though both are
+// otherwise byte-for-byte identical ZIPs. OOXML writers conventionally
+// place [Content_Types].xml first in the archive and keep it small, so it
+// is ordinarily whole even in a bounded sniffing prefix; entries this
+// function finds only partially written are treated as a non-match rather
+// than guessed at.
+func ooxmlContentType(raw []byte, marker string) bool {
+	for _, f := range walkZipLocalFiles(raw) {
+		if f.name != "[Content_Types].xml" {
+			continue
+		}
+		if f.truncated {
+			return false
+		}
+		var content []byte
+		const deflate = 8 // ZIP compression method 8: DEFLATE
+		switch f.method {
+		case 0:
+			content = f.data
+		case deflate:
+			decoded, err := inflate(f.data)
+			if err != nil {
+				return false
+			}
+			content = decoded
+		default:
+			return false
+		}
+		return bytes.Contains(content, []byte(marker))
+	}
+	return false
+}
+
+// Docm matches Microsoft Word macro-enabled documents.
+func Docm(raw []byte, limit uint32) bool {
+	return ooxmlContentType(raw, "ms-word.document.macroEnabled.main") ||
+		(ooxmlHasVBA(raw, "word/") && !ooxmlContentType(raw, "ms-word.template.macroEnabledTemplate.main"))
+}
+
+// Dotm matches Microsoft Word macro-enabled templates.
+func Dotm(raw []byte, limit uint32) bool {
+	return ooxmlContentType(raw, "ms-word.template.macroEnabledTemplate.main") ||
+		(ooxmlHasVBA(raw, "word/") && !ooxmlContentType(raw, "ms-word.document.macroEnabled.main"))
+}
+
+// Xlsm matches Microsoft Excel macro-enabled workbooks.
+func Xlsm(raw []byte, limit uint32) bool {
+	return ooxmlContentType(raw, "ms-excel.sheet.macroEnabled.main") ||
+		(ooxmlHasVBA(raw, "xl/") && !ooxmlContentType(raw, "ms-excel.template.macroEnabled.main"))
+}
+
+// Xltm matches Microsoft Excel macro-enabled templates.
+func Xltm(raw []byte, limit uint32) bool {
+	return ooxmlContentType(raw, "ms-excel.template.macroEnabled.main") ||
+		(ooxmlHasVBA(raw, "xl/") && !ooxmlContentType(raw, "ms-excel.sheet.macroEnabled.main"))
+}
+
+// Pptm matches Microsoft PowerPoint macro-enabled presentations.
+func Pptm(raw []byte, limit uint32) bool {
+	return ooxmlContentType(raw, "ms-powerpoint.presentation.macroEnabled.main") ||
+		(ooxmlHasVBA(raw, "ppt/") && !ooxmlContentType(raw, "ms-powerpoint.template.macroEnabled.main") &&
+			!ooxmlContentType(raw, "ms-powerpoint.slideshow.macroEnabled.main"))
+}
+
+// Potm matches Microsoft PowerPoint macro-enabled templates.
+func Potm(raw []byte, limit uint32) bool {
+	return ooxmlContentType(raw, "ms-powerpoint.template.macroEnabled.main") ||
+		(ooxmlHasVBA(raw, "ppt/") && !ooxmlContentType(raw, "ms-powerpoint.presentation.macroEnabled.main") &&
+			!ooxmlContentType(raw, "ms-powerpoint.slideshow.macroEnabled.main"))
+}
+
+// Ppsm matches Microsoft PowerPoint macro-enabled slideshows.
+func Ppsm(raw []byte, limit uint32) bool {
+	return ooxmlContentType(raw, "ms-powerpoint.slideshow.macroEnabled.main") ||
+		(ooxmlHasVBA(raw, "ppt/") && !ooxmlContentType(raw, "ms-powerpoint.presentation.macroEnabled.main") &&
+			!ooxmlContentType(raw, "ms-powerpoint.template.macroEnabled.main"))
+}
+
+// Dotx matches Microsoft Word templates.
+func Dotx(raw []byte, limit uint32) bool {
+	return ooxmlContentType(raw, "wordprocessingml.template")
+}
+
+// Xltx matches Microsoft Excel templates.
+func Xltx(raw []byte, limit uint32) bool {
+	return ooxmlContentType(raw, "spreadsheetml.template")
+}
+
+// Potx matches Microsoft PowerPoint templates.
+func Potx(raw []byte, limit uint32) bool {
+	return ooxmlContentType(raw, "presentationml.template")
+}
+
+// msiCLSID is the little-endian byte encoding of the root storage CLSID
+// {000C1084-0000-0000-C000-000000000046}, which the Windows Installer
+// writes into the root directory entry of every .msi it produces.
+var msiCLSID = []byte{
+	0x84, 0x10, 0x0C, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46,
+}
+
+// Msi matches Windows Installer packages: OLE compound files in which the
+// bytes of msiCLSID appear. The bytes are searched for anywhere in raw; the
+// root directory entry that holds the CLSID is not located.
+func Msi(raw []byte, limit uint32) bool {
+	return Ole(raw, limit) && bytes.Contains(raw, msiCLSID)
+}
@@ -0,0 +1,202 @@
+package magic
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+// entry is one file to lay out in a hand-built ZIP prefix. Using
+// archive/zip.Writer here would not exercise what walkZipLocalFiles
+// actually needs to handle: zip.Writer defers compressed/uncompressed
+// sizes to a trailing data descriptor unless it knows them upfront, which
+// is exactly the "sizes present in the local header" case a truncated
+// sniffing prefix depends on. Building headers by hand guarantees that.
+type entry struct {
+	name   string
+	method uint16
+	data   []byte // already compressed, if method requires it
+	rawLen int    // uncompressed length
+}
+
+func storedEntry(name string, data []byte) entry {
+	return entry{name: name, method: 0, data: data, rawLen: len(data)}
+}
+
+func deflatedEntry(t *testing.T, name string, data []byte) entry {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	w, err := flate.NewWriter(buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatalf("flate.NewWriter: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("flate write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("flate close: %v", err)
+	}
+	return entry{name: name, method: 8, data: buf.Bytes(), rawLen: len(data)}
+}
+
+// buildZip lays out entries back to back as bare local file headers, with
+// no central directory: walkZipLocalFiles never looks for one, and real
+// files are a superset of what a bounded sniffing prefix contains anyway.
+func buildZip(entries ...entry) []byte {
+	buf := &bytes.Buffer{}
+	for _, e := range entries {
+		header := make([]byte, 30)
+		binary.LittleEndian.PutUint32(header[0:4], localFileHeaderSig)
+		binary.LittleEndian.PutUint16(header[4:6], 20)
+		binary.LittleEndian.PutUint16(header[6:8], 0)
+		binary.LittleEndian.PutUint16(header[8:10], e.method)
+		binary.LittleEndian.PutUint32(header[14:18], crc32.ChecksumIEEE(e.data))
+		binary.LittleEndian.PutUint32(header[18:22], uint32(len(e.data)))
+		binary.LittleEndian.PutUint32(header[22:26], uint32(e.rawLen))
+		binary.LittleEndian.PutUint16(header[26:28], uint16(len(e.name)))
+		buf.Write(header)
+		buf.WriteString(e.name)
+		buf.Write(e.data)
+	}
+	return buf.Bytes()
+}
+
+func TestDocm(t *testing.T) {
+	contentTypes := []byte(`<Types><Override PartName="/word/document.xml" ContentType="application/vnd.ms-word.document.macroEnabled.main+xml"/></Types>`)
+
+	macroEnabled := buildZip(
+		storedEntry("[Content_Types].xml", contentTypes),
+		storedEntry("word/document.xml", []byte("<w:document/>")),
+		storedEntry("word/vbaProject.bin", []byte{0x00, 0x01}),
+	)
+	if !Docm(macroEnabled, uint32(len(macroEnabled))) {
+		t.Error("Docm() = false for a macro-enabled document, want true")
+	}
+
+	// A plain docx: different content-type family, and no VBA project.
+	plain := buildZip(
+		storedEntry("[Content_Types].xml", []byte(`<Types><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/></Types>`)),
+		storedEntry("word/document.xml", []byte("<w:document/>")),
+	)
+	if Docm(plain, uint32(len(plain))) {
+		t.Error("Docm() = true for a plain docx with no VBA project, want false")
+	}
+}
+
+// TestDocmPastVBAWindow covers the case that matters in practice: a real
+// .docm's word/vbaProject.bin part is usually written after word/media
+// images and other bulk content, well past any bounded sniffing prefix,
+// while [Content_Types].xml stays first and small. The content-type
+// Override alone must be enough; Docm must not require both signals.
+func TestDocmPastVBAWindow(t *testing.T) {
+	contentTypes := []byte(`<Types><Override PartName="/word/document.xml" ContentType="application/vnd.ms-word.document.macroEnabled.main+xml"/></Types>`)
+	docm := buildZip(
+		storedEntry("[Content_Types].xml", contentTypes),
+		storedEntry("word/document.xml", []byte("<w:document/>")),
+	)
+	if !Docm(docm, uint32(len(docm))) {
+		t.Error("Docm() = false for a macro-enabled document type with no VBA project in the prefix, want true")
+	}
+}
+
+// TestDocmTruncatedPrefix is the regression test for the central-directory
+// bug: a real .docm is usually far larger than any sniffing limit, so its
+// end-of-central-directory record is never part of raw. Detection must
+// still work from local file headers alone.
+func TestDocmTruncatedPrefix(t *testing.T) {
+	filler := bytes.Repeat([]byte("x"), 4096)
+	full := buildZip(
+		storedEntry("[Content_Types].xml", []byte(`<Types><Override PartName="/word/document.xml" ContentType="application/vnd.ms-word.document.macroEnabled.main+xml"/></Types>`)),
+		storedEntry("word/vbaProject.bin", []byte{0x00, 0x01}),
+		storedEntry("word/media/image1.png", filler),
+	)
+	prefix := full[:len(full)-len(filler)/2] // cut mid-way through the last entry's data; no central directory at all
+	if !Docm(prefix, uint32(len(prefix))) {
+		t.Error("Docm() = false against a truncated prefix with no central directory, want true")
+	}
+}
+
+func TestDeflatedContentTypes(t *testing.T) {
+	contentTypes := []byte(`<Types><Override PartName="/xl/workbook.xml" ContentType="application/vnd.ms-excel.sheet.macroEnabled.main+xml"/></Types>`)
+	xlsm := buildZip(
+		deflatedEntry(t, "[Content_Types].xml", contentTypes),
+		storedEntry("xl/workbook.xml", []byte("<workbook/>")),
+		storedEntry("xl/vbaProject.bin", []byte{0x00, 0x01}),
+	)
+	if !Xlsm(xlsm, uint32(len(xlsm))) {
+		t.Error("Xlsm() = false for a DEFLATE-compressed [Content_Types].xml, want true")
+	}
+	if Docm(xlsm, uint32(len(xlsm))) {
+		t.Error("Docm() = true for an xlsm with no word/vbaProject.bin, want false")
+	}
+	if Pptm(xlsm, uint32(len(xlsm))) {
+		t.Error("Pptm() = true for an xlsm with no ppt/vbaProject.bin, want false")
+	}
+}
+
+// TestPptmCrossApp pins the app-scoped VBA fallback the other direction:
+// a presentation's ppt/vbaProject.bin must not make the Word or Excel
+// detectors fire.
+func TestPptmCrossApp(t *testing.T) {
+	contentTypes := []byte(`<Types><Override PartName="/ppt/presentation.xml" ContentType="application/vnd.ms-powerpoint.presentation.macroEnabled.main+xml"/></Types>`)
+	pptm := buildZip(
+		storedEntry("[Content_Types].xml", contentTypes),
+		storedEntry("ppt/presentation.xml", []byte("<p:presentation/>")),
+		storedEntry("ppt/vbaProject.bin", []byte{0x00, 0x01}),
+	)
+	if !Pptm(pptm, uint32(len(pptm))) {
+		t.Error("Pptm() = false for a macro-enabled presentation, want true")
+	}
+	if Docm(pptm, uint32(len(pptm))) {
+		t.Error("Docm() = true for a pptm with no word/vbaProject.bin, want false")
+	}
+	if Xlsm(pptm, uint32(len(pptm))) {
+		t.Error("Xlsm() = true for a pptm with no xl/vbaProject.bin, want false")
+	}
+}
+
+func TestDotx(t *testing.T) {
+	dotx := buildZip(
+		storedEntry("[Content_Types].xml", []byte(`<Types><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml"/></Types>`)),
+		storedEntry("word/document.xml", []byte("<w:document/>")),
+	)
+	if !Dotx(dotx, uint32(len(dotx))) {
+		t.Error("Dotx() = false for a Word template, want true")
+	}
+	if Xlsm(dotx, uint32(len(dotx))) {
+		t.Error("Xlsm() = true for a Word template, want false")
+	}
+}
+
+// A minimal, otherwise-valid OLE compound file header: signature, then
+// zeroed fields up to the root directory entry's CLSID field at byte
+// offset 80 from the start of the root directory sector. Real parsing of
+// the FAT chain is unnecessary here: Msi only needs Ole(raw, limit) to
+// pass (which requires just the 8-byte OLE signature) and msiCLSID to
+// appear somewhere in raw, which is how the detector itself is written.
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+func buildOLE(rootCLSID []byte) []byte {
+	buf := make([]byte, 512)
+	copy(buf, oleSignature)
+	copy(buf[128+80:], rootCLSID) // arbitrary offset past the header, root-entry-shaped
+	return buf
+}
+
+func TestMsi(t *testing.T) {
+	msi := buildOLE(msiCLSID)
+	if !Msi(msi, uint32(len(msi))) {
+		t.Error("Msi() = false for an OLE file whose root CLSID is the MSI identifier, want true")
+	}
+
+	docCLSID := []byte{
+		0x06, 0x09, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46,
+	}
+	doc := buildOLE(docCLSID)
+	if Msi(doc, uint32(len(doc))) {
+		t.Error("Msi() = true for an OLE file with an unrelated root CLSID, want false")
+	}
+}
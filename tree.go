@@ -45,7 +45,13 @@ var (
 		"application/gzip-compressed", "application/x-gzip-compressed",
 		"gzip/document")
 	sevenZ = newMIME("application/x-7z-compressed", ".7z", magic.SevenZ)
-	zip    = newMIME("application/zip", ".zip", magic.Zip, xlsx, docx, pptx, epub, jar, odt, ods, odp, odg, odf, odc, sxc).
+	// The macro/template variants are tried before their generic xlsx/docx/pptx
+	// siblings: magic.Xlsx/Docx/Pptx only check for a part like xl/workbook.xml
+	// or word/document.xml, which a .xlsm or .dotx also contains, so the more
+	// specific detectors must get first refusal or they're never reached.
+	zip = newMIME("application/zip", ".zip", magic.Zip,
+		docm, dotm, xlsm, xltm, pptm, potm, ppsm, dotx, xltx, potx,
+		xlsx, docx, pptx, epub, jar, odt, ods, odp, odg, odf, odc, sxc).
 		alias("application/x-zip", "application/x-zip-compressed")
 	tar = newMIME("application/x-tar", ".tar", magic.Tar)
 	xar = newMIME("application/x-xar", ".xar", magic.Xar)
@@ -58,7 +64,18 @@ var (
 	pptx = newMIME("application/vnd.openxmlformats-officedocument.presentationml.presentation", ".pptx", magic.Pptx)
 	epub = newMIME("application/epub+zip", ".epub", magic.Epub)
 	jar  = newMIME("application/jar", ".jar", magic.Jar)
-	ole  = newMIME("application/x-ole-storage", "", magic.Ole, aaf, msg, xls, pub, ppt, doc)
+	docm = newMIME("application/vnd.ms-word.document.macroEnabled.12", ".docm", magic.Docm)
+	dotm = newMIME("application/vnd.ms-word.template.macroEnabled.12", ".dotm", magic.Dotm)
+	xlsm = newMIME("application/vnd.ms-excel.sheet.macroEnabled.12", ".xlsm", magic.Xlsm)
+	xltm = newMIME("application/vnd.ms-excel.template.macroEnabled.12", ".xltm", magic.Xltm)
+	pptm = newMIME("application/vnd.ms-powerpoint.presentation.macroEnabled.12", ".pptm", magic.Pptm)
+	potm = newMIME("application/vnd.ms-powerpoint.template.macroEnabled.12", ".potm", magic.Potm)
+	ppsm = newMIME("application/vnd.ms-powerpoint.slideshow.macroEnabled.12", ".ppsm", magic.Ppsm)
+	dotx = newMIME("application/vnd.openxmlformats-officedocument.wordprocessingml.template", ".dotx", magic.Dotx)
+	xltx = newMIME("application/vnd.openxmlformats-officedocument.spreadsheetml.template", ".xltx", magic.Xltx)
+	potx = newMIME("application/vnd.openxmlformats-officedocument.presentationml.template", ".potx", magic.Potx)
+	ole  = newMIME("application/x-ole-storage", "", magic.Ole, aaf, msg, xls, pub, ppt, doc, msi)
+	msi  = newMIME("application/x-msi", ".msi", magic.Msi)
 	aaf  = newMIME("application/octet-stream", ".aaf", magic.Aaf)
 	doc  = newMIME("application/msword", ".doc", magic.Doc).
 		alias("application/vnd.ms-word")
@@ -0,0 +1,380 @@
+package mimetype
+
+import (
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MagicSpec is a single content-sniffing rule, in the shape used by
+// freedesktop.org shared-mime-info and Nutch's mime-types.xml: at Offset,
+// match Value (optionally narrowed by Mask) against the input, interpreted
+// as Type ("byte", "string", "host16", "host32", "big16", "big32",
+// "little16" or "little32").
+type MagicSpec struct {
+	Offset uint32
+	Type   string
+	Value  []byte
+	Mask   []byte
+	// Sub holds nested specs that must all match, at their own offsets,
+	// for this spec to pass. shared-mime-info nests <match> elements this
+	// way to require several tests on the same candidate format.
+	Sub []MagicSpec
+}
+
+// findMIME returns the node whose canonical string or alias equals s, or
+// nil if the tree has none. It must be called with mu already held for
+// reading (or writing).
+func findMIME(s string) *MIME {
+	var found *MIME
+	var walk func(m *MIME)
+	walk = func(m *MIME) {
+		if found != nil {
+			return
+		}
+		if m.mime == s {
+			found = m
+			return
+		}
+		for _, a := range m.aliases {
+			if a == s {
+				found = m
+				return
+			}
+		}
+		for _, c := range m.children {
+			walk(c)
+		}
+	}
+	walk(root)
+	return found
+}
+
+// compile turns a MagicSpec, and any specs nested under it, into a detector
+// function compatible with the ones internal/magic hands to newMIME.
+func (s MagicSpec) compile() (func(raw []byte, limit uint32) bool, error) {
+	test, err := compileOne(s)
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]func([]byte, uint32) bool, 0, len(s.Sub))
+	for _, sub := range s.Sub {
+		f, err := sub.compile()
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, f)
+	}
+	return func(raw []byte, limit uint32) bool {
+		if !test(raw) {
+			return false
+		}
+		for _, f := range subs {
+			if !f(raw, limit) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func compileOne(s MagicSpec) (func([]byte) bool, error) {
+	off := int(s.Offset)
+	val := s.Value
+	mask := s.Mask
+
+	widths := map[string]int{
+		"byte": 1, "string": len(val),
+		"host16": 2, "host32": 4,
+		"big16": 2, "big32": 4,
+		"little16": 2, "little32": 4,
+	}
+	w, ok := widths[s.Type]
+	if !ok {
+		return nil, fmt.Errorf("mimetype: unsupported magic type %q", s.Type)
+	}
+	if s.Type != "string" && s.Type != "byte" && len(val) != w {
+		return nil, fmt.Errorf("mimetype: value for type %q must be %d bytes, got %d", s.Type, w, len(val))
+	}
+
+	return func(raw []byte) bool {
+		if off+w > len(raw) || w == 0 {
+			return false
+		}
+		got := raw[off : off+w]
+		for i, b := range got {
+			gb := b
+			vb := val[i]
+			if mask != nil && i < len(mask) {
+				gb &= mask[i]
+				vb &= mask[i]
+			}
+			if gb != vb {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// RegisterFromMagic compiles specs into a single detector and inserts a new
+// node for mimeType into the tree, as a child of parent (root if nil),
+// carrying extension as its canonical file extension and aliases as
+// additional names the node answers to. It returns the newly created node.
+//
+// Multiple specs are alternatives of each other (any one matching is
+// enough); use MagicSpec.Sub to require several tests on the same
+// candidate, mirroring how shared-mime-info nests <match> elements.
+func RegisterFromMagic(mimeType, extension string, parent *MIME, specs []MagicSpec, aliases ...string) (*MIME, error) {
+	tests := make([]func([]byte, uint32) bool, 0, len(specs))
+	for _, spec := range specs {
+		f, err := spec.compile()
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, f)
+	}
+	detector := func(raw []byte, limit uint32) bool {
+		for _, f := range tests {
+			if f(raw, limit) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Build the index (if it hasn't been already) before taking mu for
+	// writing below: buildExtIndex takes mu.RLock itself, and Go's
+	// RWMutex is not reentrant, so calling it after mu.Lock would
+	// deadlock.
+	buildExtIndex()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if parent == nil {
+		parent = root
+	}
+	node := newMIME(mimeType, extension, detector)
+	if len(aliases) > 0 {
+		node.alias(aliases...)
+	}
+	parent.children = append(parent.children, node)
+	node.parent = parent
+
+	if extToMIME != nil && extension != "" {
+		ext := strings.ToLower(extension)
+		if _, ok := extToMIME[ext]; !ok {
+			extToMIME[ext] = node
+			mimeToExts[mimeType] = append(mimeToExts[mimeType], ext)
+		}
+	}
+	return node, nil
+}
+
+// xmlMimeInfo mirrors the root element of shared-mime-info's
+// freedesktop.org.xml and of Nutch's mime-types.xml, which share the same
+// <mime-info>/<mime-type>/<magic>/<match> shape.
+type xmlMimeInfo struct {
+	XMLName   xml.Name      `xml:"mime-info"`
+	MimeTypes []xmlMIMEType `xml:"mime-type"`
+}
+
+type xmlMIMEType struct {
+	Type       string        `xml:"type,attr"`
+	Aliases    []xmlAlias    `xml:"alias"`
+	SubClassOf []xmlSubClass `xml:"sub-class-of"`
+	Globs      []xmlGlob     `xml:"glob"`
+	Magics     []xmlMagic    `xml:"magic"`
+}
+
+type xmlAlias struct {
+	Type string `xml:"type,attr"`
+}
+
+type xmlSubClass struct {
+	Type string `xml:"type,attr"`
+}
+
+type xmlGlob struct {
+	Pattern string `xml:"pattern,attr"`
+}
+
+type xmlMagic struct {
+	Matches []xmlMatch `xml:"match"`
+}
+
+type xmlMatch struct {
+	Type    string     `xml:"type,attr"`
+	Offset  string     `xml:"offset,attr"`
+	Value   string     `xml:"value,attr"`
+	Mask    string     `xml:"mask,attr"`
+	Matches []xmlMatch `xml:"match"`
+}
+
+// toSpec converts a decoded <match> (and its nested <match> children) into
+// a MagicSpec. Offset accepts a single integer, as both shared-mime-info and
+// Nutch's schema restrict ranges ("lo:hi") to a feature LoadFromXML does not
+// support; such entries are rejected with an error naming the offending type.
+func (m xmlMatch) toSpec() (MagicSpec, error) {
+	if strings.Contains(m.Offset, ":") {
+		return MagicSpec{}, fmt.Errorf("mimetype: offset ranges are not supported, got %q", m.Offset)
+	}
+	offset, err := strconv.ParseUint(m.Offset, 10, 32)
+	if err != nil {
+		return MagicSpec{}, fmt.Errorf("mimetype: invalid offset %q: %w", m.Offset, err)
+	}
+
+	value, err := parseMagicValue(m.Type, m.Value)
+	if err != nil {
+		return MagicSpec{}, err
+	}
+	var mask []byte
+	if m.Mask != "" {
+		mask, err = parseHexOrRaw(m.Mask)
+		if err != nil {
+			return MagicSpec{}, fmt.Errorf("mimetype: invalid mask %q: %w", m.Mask, err)
+		}
+	}
+
+	spec := MagicSpec{Offset: uint32(offset), Type: m.Type, Value: value, Mask: mask}
+	for _, sub := range m.Matches {
+		subSpec, err := sub.toSpec()
+		if err != nil {
+			return MagicSpec{}, err
+		}
+		spec.Sub = append(spec.Sub, subSpec)
+	}
+	return spec, nil
+}
+
+func parseMagicValue(typ, value string) ([]byte, error) {
+	switch typ {
+	case "string":
+		return parseHexOrRaw(value)
+	case "byte":
+		n, err := strconv.ParseUint(value, 0, 8)
+		if err != nil {
+			return parseHexOrRaw(value)
+		}
+		return []byte{byte(n)}, nil
+	case "host16", "big16", "little16", "host32", "big32", "little32":
+		n, err := strconv.ParseUint(value, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("mimetype: invalid numeric value %q for type %q: %w", value, typ, err)
+		}
+		buf := make([]byte, 8)
+		order := binary.BigEndian
+		if strings.HasPrefix(typ, "little") || (strings.HasPrefix(typ, "host") && littleEndianHost) {
+			order = binary.LittleEndian
+		}
+		order.PutUint64(buf, n)
+		width := 2
+		if strings.HasSuffix(typ, "32") {
+			width = 4
+		}
+		if order == binary.BigEndian {
+			return buf[8-width:], nil
+		}
+		return buf[:width], nil
+	default:
+		return nil, fmt.Errorf("mimetype: unsupported magic type %q", typ)
+	}
+}
+
+// parseHexOrRaw decodes shared-mime-info's convention of embedding non-ASCII
+// bytes in <value>/<mask> as \xHH escapes, alongside literal characters.
+func parseHexOrRaw(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			b, err := strconv.ParseUint(s[i+2:i+4], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("mimetype: invalid \\x escape in %q: %w", s, err)
+			}
+			out = append(out, byte(b))
+			i += 3
+			continue
+		}
+		out = append(out, s[i])
+	}
+	return out, nil
+}
+
+// littleEndianHost assumes a little-endian host, matching every platform
+// this module currently ships binaries for; it only affects the "host16"
+// and "host32" shared-mime-info magic types.
+const littleEndianHost = true
+
+// LoadFromXML parses freedesktop.org shared-mime-info XML (or the
+// equivalent Nutch mime-types.xml schema) from r and inserts a node for
+// every <mime-type> it describes, honoring <sub-class-of> to place it under
+// the right parent, <alias> to populate its alias set, <glob> to extend the
+// extension index used by LookupByExtension and ExtensionsFor, and <magic>
+// to compile its content detector. Entries whose <sub-class-of> names a MIME
+// type not yet known to the tree are attached under root.
+func LoadFromXML(r io.Reader) error {
+	var info xmlMimeInfo
+	if err := xml.NewDecoder(r).Decode(&info); err != nil {
+		return fmt.Errorf("mimetype: parsing shared-mime-info XML: %w", err)
+	}
+
+	for _, mt := range info.MimeTypes {
+		var specs []MagicSpec
+		for _, magic := range mt.Magics {
+			for _, match := range magic.Matches {
+				spec, err := match.toSpec()
+				if err != nil {
+					return fmt.Errorf("mimetype: %s: %w", mt.Type, err)
+				}
+				specs = append(specs, spec)
+			}
+		}
+
+		var extension string
+		for _, g := range mt.Globs {
+			if strings.HasPrefix(g.Pattern, "*.") {
+				extension = g.Pattern[1:]
+				break
+			}
+		}
+
+		mu.RLock()
+		var parent *MIME
+		for _, sc := range mt.SubClassOf {
+			if p := findMIME(sc.Type); p != nil {
+				parent = p
+				break
+			}
+		}
+		mu.RUnlock()
+
+		aliases := make([]string, 0, len(mt.Aliases))
+		for _, a := range mt.Aliases {
+			aliases = append(aliases, a.Type)
+		}
+
+		node, err := RegisterFromMagic(mt.Type, extension, parent, specs, aliases...)
+		if err != nil {
+			return fmt.Errorf("mimetype: %s: %w", mt.Type, err)
+		}
+
+		mu.Lock()
+		for _, g := range mt.Globs {
+			if !strings.HasPrefix(g.Pattern, "*.") {
+				continue
+			}
+			ext := strings.ToLower(g.Pattern[1:])
+			if extToMIME != nil {
+				if _, ok := extToMIME[ext]; !ok {
+					extToMIME[ext] = node
+					mimeToExts[mt.Type] = append(mimeToExts[mt.Type], ext)
+				}
+			}
+		}
+		mu.Unlock()
+	}
+	return nil
+}
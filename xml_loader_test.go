@@ -0,0 +1,52 @@
+package mimetype
+
+import (
+	"strings"
+	"testing"
+)
+
+const testMimeInfoXML = `<?xml version="1.0" encoding="UTF-8"?>
+<mime-info>
+  <mime-type type="application/x-mimetype-loadtest">
+    <alias type="application/x-mimetype-loadtest-alias"/>
+    <sub-class-of type="text/plain"/>
+    <glob pattern="*.loadtest"/>
+    <magic priority="50">
+      <match type="string" offset="0" value="LOADTEST"/>
+    </magic>
+  </mime-type>
+</mime-info>`
+
+func TestLoadFromXML(t *testing.T) {
+	if err := LoadFromXML(strings.NewReader(testMimeInfoXML)); err != nil {
+		t.Fatalf("LoadFromXML: %v", err)
+	}
+
+	mu.RLock()
+	node := findMIME("application/x-mimetype-loadtest")
+	mu.RUnlock()
+	if node == nil {
+		t.Fatal("LoadFromXML did not insert a node for application/x-mimetype-loadtest")
+	}
+	if node.parent != text {
+		t.Errorf("node.parent = %v, want text (from <sub-class-of type=%q>)", node.parent, "text/plain")
+	}
+
+	mu.RLock()
+	found := findMIME("application/x-mimetype-loadtest-alias")
+	mu.RUnlock()
+	if found != node {
+		t.Errorf("findMIME(alias) = %v, want the same node registered for the canonical type", found)
+	}
+
+	if !node.detector([]byte("LOADTEST"), 8) {
+		t.Error("compiled detector did not match the declared magic value")
+	}
+	if node.detector([]byte("nope"), 4) {
+		t.Error("compiled detector matched input that should not pass")
+	}
+
+	if got := LookupByExtension(".loadtest"); got != node {
+		t.Errorf("LookupByExtension(%q) = %v, want the node registered by the <glob>", ".loadtest", got)
+	}
+}